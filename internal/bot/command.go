@@ -0,0 +1,16 @@
+// Package bot provides a pluggable command dispatcher for the Telegram bot,
+// so that adding a new command doesn't require another branch in the
+// webhook handler.
+package bot
+
+import "context"
+
+// Command is a single bot command, registered with a Dispatcher under its
+// Name. Handle returns the text to reply with; a non-nil error represents an
+// unexpected failure (as opposed to an expected "not found"/"bad input"
+// reply, which should just be returned as the string).
+type Command interface {
+    Name() string
+    Help() string
+    Handle(ctx context.Context, chatID int64, args string) (string, error)
+}
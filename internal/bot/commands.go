@@ -0,0 +1,197 @@
+package bot
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/Pr3d4dor/telegram-ygoprices-bot/pkg/watchlist"
+    "github.com/Pr3d4dor/telegram-ygoprices-bot/pkg/ygoprices"
+)
+
+// PriceForPrintTagFunc fetches a card's prices for a single YgoPrices print tag
+type PriceForPrintTagFunc func(printTag string) (*ygoprices.PriceForPrintTagResponse, error)
+
+func formatPriceReply(body *ygoprices.PriceForPrintTagResponse) string {
+    prices := body.Data.PriceData.PriceData.Data.Prices
+
+    return fmt.Sprintf("Prices\nHigh :$%.2f\nAverage: $%.2f\nLow: $%.2f", prices.High, prices.Average, prices.Low)
+}
+
+// PricePrintTagCommand looks up a card's price by its exact YgoPrices print tag
+type PricePrintTagCommand struct {
+    PriceForPrintTag PriceForPrintTagFunc
+}
+
+func (c *PricePrintTagCommand) Name() string { return "priceprinttag" }
+func (c *PricePrintTagCommand) Help() string { return "<printtag> - price for a card's exact print tag" }
+
+func (c *PricePrintTagCommand) Handle(ctx context.Context, chatID int64, args string) (string, error) {
+    printTag := strings.TrimSpace(args)
+    if printTag == "" {
+        return "Error fetching card price!", nil
+    }
+
+    response, err := c.PriceForPrintTag(printTag)
+    if err != nil {
+        return "", err
+    }
+    if response == nil {
+        return "Error fetching card price!", nil
+    }
+
+    return formatPriceReply(response), nil
+}
+
+// priceCommandConfidentDistance is the maximum Levenshtein distance a top
+// match can have and still be auto-resolved instead of prompting "Did you
+// mean"
+const priceCommandConfidentDistance = 3
+
+// PriceCommand resolves a human-typed, possibly misspelled card name and
+// aggregates its price across every print edition
+type PriceCommand struct {
+    CardDB           *ygoprices.CardDatabase
+    PriceForPrintTag PriceForPrintTagFunc
+}
+
+func (c *PriceCommand) Name() string { return "price" }
+func (c *PriceCommand) Help() string { return "<card name> - fuzzy-matched price summary across editions" }
+
+func (c *PriceCommand) Handle(ctx context.Context, chatID int64, args string) (string, error) {
+    query := strings.TrimSpace(args)
+    if query == "" {
+        return "Usage: /price <card name>", nil
+    }
+
+    matches := c.CardDB.Search(query, 3)
+    if len(matches) == 0 {
+        return fmt.Sprintf("No card found matching %q", query), nil
+    }
+
+    best := matches[0]
+    ambiguous := len(matches) > 1 && matches[1].Distance == best.Distance
+    if best.Distance > priceCommandConfidentDistance || ambiguous {
+        names := make([]string, len(matches))
+        for i, match := range matches {
+            names[i] = match.Name
+        }
+
+        return "Did you mean:\n" + strings.Join(names, "\n"), nil
+    }
+
+    return c.summarize(best.CardMatch)
+}
+
+func (c *PriceCommand) summarize(match ygoprices.CardMatch) (string, error) {
+    var averages []float64
+    var high, low float64
+    first := true
+
+    for _, printTag := range match.PrintTags {
+        response, err := c.PriceForPrintTag(printTag)
+        if err != nil || response == nil {
+            continue
+        }
+
+        prices := response.Data.PriceData.PriceData.Data.Prices
+        averages = append(averages, prices.Average)
+
+        if first || prices.High > high {
+            high = prices.High
+        }
+        if first || prices.Low < low {
+            low = prices.Low
+        }
+        first = false
+    }
+
+    if len(averages) == 0 {
+        return "", errors.New("no pricing data available")
+    }
+
+    sort.Float64s(averages)
+    median := averages[len(averages)/2]
+    if len(averages)%2 == 0 {
+        median = (averages[len(averages)/2-1] + median) / 2
+    }
+
+    return fmt.Sprintf("%s\nHigh: $%.2f\nMedian: $%.2f\nLow: $%.2f\n(%d editions)", match.Name, high, median, low, len(averages)), nil
+}
+
+// WatchCommand registers a print tag to a chat's watchlist with an alert threshold
+type WatchCommand struct {
+    Store *watchlist.Store
+}
+
+func (c *WatchCommand) Name() string { return "watch" }
+func (c *WatchCommand) Help() string { return "<printtag> <threshold> - alert when the average price crosses threshold" }
+
+func (c *WatchCommand) Handle(ctx context.Context, chatID int64, args string) (string, error) {
+    parts := strings.Fields(args)
+    if len(parts) < 2 {
+        return "Usage: /watch <printtag> <threshold>", nil
+    }
+
+    threshold, err := strconv.ParseFloat(parts[1], 64)
+    if err != nil {
+        return "Threshold must be a number", nil
+    }
+
+    printTag := parts[0]
+    if err := c.Store.Add(chatID, watchlist.Entry{PrintTag: printTag, Threshold: threshold}); err != nil {
+        return "", err
+    }
+
+    return fmt.Sprintf("Watching %s, will alert at $%.2f", printTag, threshold), nil
+}
+
+// UnwatchCommand removes a print tag from a chat's watchlist
+type UnwatchCommand struct {
+    Store *watchlist.Store
+}
+
+func (c *UnwatchCommand) Name() string { return "unwatch" }
+func (c *UnwatchCommand) Help() string { return "<printtag> - stop watching a print tag" }
+
+func (c *UnwatchCommand) Handle(ctx context.Context, chatID int64, args string) (string, error) {
+    printTag := strings.TrimSpace(args)
+    if printTag == "" {
+        return "Usage: /unwatch <printtag>", nil
+    }
+
+    if err := c.Store.Remove(chatID, printTag); err != nil {
+        return "", err
+    }
+
+    return fmt.Sprintf("No longer watching %s", printTag), nil
+}
+
+// WatchlistCommand lists everything a chat is currently watching
+type WatchlistCommand struct {
+    Store *watchlist.Store
+}
+
+func (c *WatchlistCommand) Name() string { return "watchlist" }
+func (c *WatchlistCommand) Help() string { return "- list the print tags you're watching" }
+
+func (c *WatchlistCommand) Handle(ctx context.Context, chatID int64, args string) (string, error) {
+    entries, err := c.Store.List(chatID)
+    if err != nil {
+        return "", err
+    }
+
+    if len(entries) == 0 {
+        return "Your watchlist is empty", nil
+    }
+
+    lines := make([]string, len(entries))
+    for i, entry := range entries {
+        lines[i] = fmt.Sprintf("%s (alert at $%.2f)", entry.PrintTag, entry.Threshold)
+    }
+
+    return "Watchlist:\n" + strings.Join(lines, "\n"), nil
+}
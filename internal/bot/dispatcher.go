@@ -0,0 +1,84 @@
+package bot
+
+import (
+    "context"
+    "fmt"
+    "strings"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+// Middleware wraps a Command with cross-cutting behavior (logging, rate
+// limiting, panic recovery, metrics, ...)
+type Middleware func(Command) Command
+
+// Dispatcher parses "/command args" out of an incoming message, looks up the
+// matching Command by name, and runs it through the configured middleware
+// chain
+type Dispatcher struct {
+    logger     hclog.Logger
+    middleware []Middleware
+    commands   map[string]Command
+    order      []string
+}
+
+// NewDispatcher creates a Dispatcher. Middleware is applied in the order
+// given, with the first middleware seeing the request first.
+func NewDispatcher(logger hclog.Logger, middleware ...Middleware) *Dispatcher {
+    return &Dispatcher{
+        logger:     logger,
+        middleware: middleware,
+        commands:   map[string]Command{},
+    }
+}
+
+// Register wraps cmd in the Dispatcher's middleware chain and adds it to the
+// registry, keyed by its (lowercased) Name
+func (d *Dispatcher) Register(cmd Command) {
+    wrapped := cmd
+    for i := len(d.middleware) - 1; i >= 0; i-- {
+        wrapped = d.middleware[i](wrapped)
+    }
+
+    name := strings.ToLower(cmd.Name())
+    d.commands[name] = wrapped
+    d.order = append(d.order, name)
+}
+
+// Dispatch parses text as "/command args...", runs the matching registered
+// Command, and auto-generates the /help reply from the registered commands
+func (d *Dispatcher) Dispatch(ctx context.Context, chatID int64, text string) (string, error) {
+    name, args := parseCommand(text)
+    if name == "help" {
+        return d.helpText(), nil
+    }
+
+    cmd, ok := d.commands[name]
+    if !ok {
+        return "Invalid command!", nil
+    }
+
+    return cmd.Handle(ctx, chatID, args)
+}
+
+func (d *Dispatcher) helpText() string {
+    lines := make([]string, 0, len(d.order)+1)
+    lines = append(lines, "Available commands:")
+    for _, name := range d.order {
+        lines = append(lines, fmt.Sprintf("/%s %s", name, d.commands[name].Help()))
+    }
+
+    return strings.Join(lines, "\n")
+}
+
+// parseCommand splits "/name rest of args" into its lowercased name (with
+// the leading slash stripped) and the remaining args
+func parseCommand(text string) (name string, args string) {
+    parts := strings.SplitN(strings.TrimSpace(text), " ", 2)
+    name = strings.ToLower(strings.TrimPrefix(parts[0], "/"))
+    if len(parts) > 1 {
+        args = strings.TrimSpace(parts[1])
+    }
+
+    return name, args
+}
@@ -0,0 +1,132 @@
+package bot
+
+import (
+    "context"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+func TestParseCommand(t *testing.T) {
+    tests := []struct {
+        text     string
+        wantName string
+        wantArgs string
+    }{
+        {"/price dark magician", "price", "dark magician"},
+        {"/PriceCommand Foo", "pricecommand", "Foo"},
+        {"/help", "help", ""},
+        {"  /watch 12345 1.50  ", "watch", "12345 1.50"},
+        {"not a command", "not", "a command"},
+        {"", "", ""},
+    }
+
+    for _, tt := range tests {
+        name, args := parseCommand(tt.text)
+        if name != tt.wantName || args != tt.wantArgs {
+            t.Errorf("parseCommand(%q) = (%q, %q), want (%q, %q)", tt.text, name, args, tt.wantName, tt.wantArgs)
+        }
+    }
+}
+
+func TestDispatcherDispatchUnknownCommand(t *testing.T) {
+    d := NewDispatcher(hclog.NewNullLogger())
+
+    reply, err := d.Dispatch(context.Background(), 1, "/nope")
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if reply != "Invalid command!" {
+        t.Errorf("reply = %q, want %q", reply, "Invalid command!")
+    }
+}
+
+func TestDispatcherDispatchHelp(t *testing.T) {
+    d := NewDispatcher(hclog.NewNullLogger())
+    d.Register(commandFunc{
+        name: "ping",
+        help: "- replies pong",
+        handle: func(ctx context.Context, chatID int64, args string) (string, error) {
+            return "pong", nil
+        },
+    })
+
+    reply, err := d.Dispatch(context.Background(), 1, "/help")
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    want := "Available commands:\n/ping - replies pong"
+    if reply != want {
+        t.Errorf("reply = %q, want %q", reply, want)
+    }
+}
+
+func TestDispatcherDispatchRoutesToRegisteredCommand(t *testing.T) {
+    d := NewDispatcher(hclog.NewNullLogger())
+
+    var gotChatID int64
+    var gotArgs string
+    d.Register(commandFunc{
+        name: "echo",
+        help: "<text> - echoes text",
+        handle: func(ctx context.Context, chatID int64, args string) (string, error) {
+            gotChatID = chatID
+            gotArgs = args
+
+            return "echo: " + args, nil
+        },
+    })
+
+    reply, err := d.Dispatch(context.Background(), 42, "/ECHO hello there")
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if reply != "echo: hello there" {
+        t.Errorf("reply = %q, want %q", reply, "echo: hello there")
+    }
+    if gotChatID != 42 {
+        t.Errorf("chatID = %d, want 42", gotChatID)
+    }
+    if gotArgs != "hello there" {
+        t.Errorf("args = %q, want %q", gotArgs, "hello there")
+    }
+}
+
+func TestDispatcherRegisterAppliesMiddlewareInOrder(t *testing.T) {
+    var calls []string
+    mark := func(name string) Middleware {
+        return func(next Command) Command {
+            return wrap(next, func(ctx context.Context, chatID int64, args string) (string, error) {
+                calls = append(calls, name)
+
+                return next.Handle(ctx, chatID, args)
+            })
+        }
+    }
+
+    d := NewDispatcher(hclog.NewNullLogger(), mark("outer"), mark("inner"))
+    d.Register(commandFunc{
+        name: "noop",
+        help: "",
+        handle: func(ctx context.Context, chatID int64, args string) (string, error) {
+            calls = append(calls, "handler")
+
+            return "", nil
+        },
+    })
+
+    if _, err := d.Dispatch(context.Background(), 1, "/noop"); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    want := []string{"outer", "inner", "handler"}
+    if len(calls) != len(want) {
+        t.Fatalf("calls = %v, want %v", calls, want)
+    }
+    for i := range want {
+        if calls[i] != want[i] {
+            t.Errorf("calls = %v, want %v", calls, want)
+            break
+        }
+    }
+}
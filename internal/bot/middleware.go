@@ -0,0 +1,88 @@
+package bot
+
+import (
+    "context"
+    "fmt"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+// commandFunc adapts a name, help text and handle closure into a Command,
+// so middleware can wrap a Command without declaring a new named type
+type commandFunc struct {
+    name   string
+    help   string
+    handle func(ctx context.Context, chatID int64, args string) (string, error)
+}
+
+func (c commandFunc) Name() string { return c.name }
+func (c commandFunc) Help() string { return c.help }
+func (c commandFunc) Handle(ctx context.Context, chatID int64, args string) (string, error) {
+    return c.handle(ctx, chatID, args)
+}
+
+func wrap(next Command, handle func(ctx context.Context, chatID int64, args string) (string, error)) Command {
+    return commandFunc{name: next.Name(), help: next.Help(), handle: handle}
+}
+
+// LoggingMiddleware logs the outcome of every command invocation
+func LoggingMiddleware(logger hclog.Logger) Middleware {
+    return func(next Command) Command {
+        return wrap(next, func(ctx context.Context, chatID int64, args string) (string, error) {
+            reply, err := next.Handle(ctx, chatID, args)
+            if err != nil {
+                logger.Error("command failed", "command", next.Name(), "chat_id", chatID, "error", err)
+            } else {
+                logger.Info("command handled", "command", next.Name(), "chat_id", chatID)
+            }
+
+            return reply, err
+        })
+    }
+}
+
+// RecoverMiddleware converts a panic inside a Command into an error instead
+// of crashing the webhook handler
+func RecoverMiddleware(logger hclog.Logger) Middleware {
+    return func(next Command) Command {
+        return wrap(next, func(ctx context.Context, chatID int64, args string) (reply string, err error) {
+            defer func() {
+                if r := recover(); r != nil {
+                    logger.Error("command panicked", "command", next.Name(), "chat_id", chatID, "panic", r)
+                    err = fmt.Errorf("internal error handling /%s", next.Name())
+                }
+            }()
+
+            return next.Handle(ctx, chatID, args)
+        })
+    }
+}
+
+// MetricsMiddleware calls record with a command's name every time it runs,
+// letting the caller track per-command counters without this package
+// depending on a particular metrics library
+func MetricsMiddleware(record func(commandName string)) Middleware {
+    return func(next Command) Command {
+        return wrap(next, func(ctx context.Context, chatID int64, args string) (string, error) {
+            record(next.Name())
+
+            return next.Handle(ctx, chatID, args)
+        })
+    }
+}
+
+// RateLimitMiddleware applies a per-chat token bucket so a single chat can't
+// hammer the YgoPrices upstream through repeated commands
+func RateLimitMiddleware(ratePerSecond float64, burst int) Middleware {
+    limiter := newChatRateLimiter(ratePerSecond, burst)
+
+    return func(next Command) Command {
+        return wrap(next, func(ctx context.Context, chatID int64, args string) (string, error) {
+            if !limiter.Allow(chatID) {
+                return "Too many requests, please slow down!", nil
+            }
+
+            return next.Handle(ctx, chatID, args)
+        })
+    }
+}
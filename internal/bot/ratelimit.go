@@ -0,0 +1,37 @@
+package bot
+
+import (
+    "sync"
+
+    "golang.org/x/time/rate"
+)
+
+// chatRateLimiter keeps one token bucket per chat ID
+type chatRateLimiter struct {
+    mu       sync.Mutex
+    limiters map[int64]*rate.Limiter
+    rate     rate.Limit
+    burst    int
+}
+
+func newChatRateLimiter(ratePerSecond float64, burst int) *chatRateLimiter {
+    return &chatRateLimiter{
+        limiters: map[int64]*rate.Limiter{},
+        rate:     rate.Limit(ratePerSecond),
+        burst:    burst,
+    }
+}
+
+// Allow reports whether chatID has a token available, creating its bucket on
+// first use
+func (c *chatRateLimiter) Allow(chatID int64) bool {
+    c.mu.Lock()
+    limiter, ok := c.limiters[chatID]
+    if !ok {
+        limiter = rate.NewLimiter(c.rate, c.burst)
+        c.limiters[chatID] = limiter
+    }
+    c.mu.Unlock()
+
+    return limiter.Allow()
+}
@@ -6,8 +6,10 @@ import (
     "encoding/json"
     "errors"
     "fmt"
+    "math"
     "net/http"
     "strings"
+    "sync"
     "time"
     "os"
     "os/signal"
@@ -16,11 +18,29 @@ import (
 
     gohandlers "github.com/gorilla/handlers"
     "github.com/gorilla/mux"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+
+    "github.com/Pr3d4dor/telegram-ygoprices-bot/internal/bot"
+    "github.com/Pr3d4dor/telegram-ygoprices-bot/pkg/watchlist"
+    "github.com/Pr3d4dor/telegram-ygoprices-bot/pkg/ygoprices"
 )
 
+// watchlistShiftAlertPct is the fractional move in average price (relative
+// to the last observed average) that triggers an alert even without a
+// configured threshold being crossed
+const watchlistShiftAlertPct = 0.10
+
 var port = os.Getenv("PORT")
 var telegramBotApiToken = os.Getenv("TELEGRAM_BOT_API_TOKEN")
 var logger = hclog.Default()
+var ygoPricesClient = ygoprices.NewClient(logger)
+var cardDB = ygoprices.NewCardDatabase(logger)
+var watchlistStore *watchlist.Store
+var watchlistWG sync.WaitGroup
+var dispatcher *bot.Dispatcher
+
+var cardDBCachePath = os.Getenv("CARD_DB_CACHE_PATH")
+var watchlistDBPath = os.Getenv("WATCHLIST_DB_PATH")
 
 // Struct that mimics the webhook response body
 // https://core.telegram.org/bots/api#update
@@ -31,44 +51,39 @@ type webhookReqBody struct {
             ID int64 `json:"id"`
         } `json:"chat"`
     } `json:"message"`
+    InlineQuery struct {
+        ID    string `json:"id"`
+        Query string `json:"query"`
+        From  struct {
+            ID int64 `json:"id"`
+        } `json:"from"`
+    } `json:"inline_query"`
 }
 
-// Struct that mimics the webhook response body
-// https://yugiohprices.docs.apiary.io/#reference/checking-card-prices/check-price-for-cards-print-tag/check-price-for-card's-print-tag
-type ygoPricesPriceForPrintTagResponse struct {
-    Status string `json:"status"`
-    Data   struct {
-        Name      string      `json:"name"`
-        CardType  string      `json:"card_type"`
-        Property  interface{} `json:"property"`
-        Family    string      `json:"family"`
-        Type      string      `json:"type"`
-        PriceData struct {
-            Name      string `json:"name"`
-            PrintTag  string `json:"print_tag"`
-            Rarity    string `json:"rarity"`
-            PriceData struct {
-                Status string `json:"status"`
-                Data   struct {
-                    Listings []interface{} `json:"listings"`
-                    Prices   struct {
-                        High      float64 `json:"high"`
-                        Low       float64 `json:"low"`
-                        Average   float64 `json:"average"`
-                        Shift     float64 `json:"shift"`
-                        Shift3    float64 `json:"shift_3"`
-                        Shift7    float64 `json:"shift_7"`
-                        Shift21   float64 `json:"shift_21"`
-                        Shift30   float64 `json:"shift_30"`
-                        Shift90   float64 `json:"shift_90"`
-                        Shift180  float64 `json:"shift_180"`
-                        Shift365  float64 `json:"shift_365"`
-                        UpdatedAt string  `json:"updated_at"`
-                    } `json:"prices"`
-                } `json:"data"`
-            } `json:"price_data"`
-        } `json:"price_data"`
-    } `json:"data"`
+// Struct to conform to the JSON body of the input message content of an
+// inline query result
+// https://core.telegram.org/bots/api#inputtextmessagecontent
+type APIInputTextMessageContent struct {
+    MessageText string `json:"message_text"`
+}
+
+// Struct to conform to the JSON body of an inline query result article
+// https://core.telegram.org/bots/api#inlinequeryresultarticle
+type APIInlineQueryResultArticle struct {
+    Type                string                     `json:"type"`
+    ID                  string                     `json:"id"`
+    Title               string                     `json:"title"`
+    Description         string                     `json:"description,omitempty"`
+    ThumbURL            string                     `json:"thumb_url,omitempty"`
+    InputMessageContent APIInputTextMessageContent `json:"input_message_content"`
+}
+
+// Struct to conform to the JSON body of the answerInlineQuery request
+// https://core.telegram.org/bots/api#answerinlinequery
+type answerInlineQueryReqBody struct {
+    InlineQueryID string                        `json:"inline_query_id"`
+    Results       []APIInlineQueryResultArticle `json:"results"`
+    CacheTime     int                           `json:"cache_time"`
 }
 
 // Struct to conform to the JSON body of the send message request
@@ -95,42 +110,136 @@ func sendReply(chatID int64, text string) error {
     var botApiUrl = fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", telegramBotApiToken)
     res, err := http.Post(botApiUrl, "application/json", bytes.NewBuffer(reqBytes))
     if err != nil {
+        replySendFailuresTotal.Inc()
         return err
     }
     if res.StatusCode != http.StatusOK {
+        replySendFailuresTotal.Inc()
         return errors.New("unexpected status" + res.Status)
     }
 
     return nil
 }
 
-// Fetch Card Prices from YgoPrices
-func fetchCardPriceByPrintTag(printTag string) (*ygoPricesPriceForPrintTagResponse, error) {
-    resp, err1 := http.Get("https://yugiohprices.com/api/price_for_print_tag/" + printTag)
-    if err1 != nil {
-        logger.Error("Ygoprices price for print tag request error", "error", err1)
+// inlineQueryCacheEntry holds the previously computed results for a given
+// inline query string so that repeated keystrokes don't hammer YgoPrices
+type inlineQueryCacheEntry struct {
+    results   []APIInlineQueryResultArticle
+    expiresAt time.Time
+}
+
+var inlineQueryCacheTTL = 30 * time.Second
+var inlineQueryCacheMu sync.Mutex
+var inlineQueryCache = map[string]inlineQueryCacheEntry{}
+
+// inlineQueryDebounceDelay is how long buildInlineQueryResults waits before
+// actually querying YgoPrices, so that fast keystrokes coalesce into a
+// single upstream lookup for the last query a user typed
+const inlineQueryDebounceDelay = 400 * time.Millisecond
 
-        return nil, err1
+var inlineQueryGenerationMu sync.Mutex
+var inlineQueryGeneration = map[int64]uint64{}
+
+// Build the inline query results for a given query string, debounced per
+// user (so fast keystrokes coalesce into one upstream lookup) and cached
+// through inlineQueryCache so identical queries within inlineQueryCacheTTL
+// are free
+func buildInlineQueryResults(userID int64, query string) []APIInlineQueryResultArticle {
+    query = strings.TrimSpace(strings.ToLower(query))
+    if query == "" {
+        return []APIInlineQueryResultArticle{}
     }
-    defer resp.Body.Close()
 
-    // First, decode the JSON response body
-    ygoPricesPriceForPrintTagResponse := &ygoPricesPriceForPrintTagResponse{}
-    if err := json.NewDecoder(resp.Body).Decode(ygoPricesPriceForPrintTagResponse); err != nil {
-        logger.Error("Parse ygoprices price for print tag response", "error", err)
+    inlineQueryCacheMu.Lock()
+    if entry, ok := inlineQueryCache[query]; ok && time.Now().Before(entry.expiresAt) {
+        inlineQueryCacheMu.Unlock()
+        return entry.results
+    }
+    inlineQueryCacheMu.Unlock()
+
+    if superseded := waitUnlessSuperseded(userID); superseded {
+        return []APIInlineQueryResultArticle{}
+    }
+
+    results := []APIInlineQueryResultArticle{}
 
-        return nil, err
+    cardData, err := fetchCardDataMetered(query)
+    if err != nil || cardData == nil {
+        return results
     }
 
-    if ygoPricesPriceForPrintTagResponse.Status == "success" {
-        return ygoPricesPriceForPrintTagResponse, nil
+    for _, cardSet := range cardData.Data.CardSets {
+        priceResponse, err := fetchCardPriceByPrintTagMetered(cardSet.PrintTag)
+        if err != nil || priceResponse == nil {
+            continue
+        }
+
+        reply := convertYgoPricesPriceForPrintTagResponseToReply(priceResponse)
+        results = append(results, APIInlineQueryResultArticle{
+            Type:        "article",
+            ID:          cardSet.PrintTag,
+            Title:       fmt.Sprintf("%s (%s)", cardData.Data.Name, cardSet.PrintTag),
+            Description: cardSet.Rarity,
+            ThumbURL:    cardData.Data.Image,
+            InputMessageContent: APIInputTextMessageContent{
+                MessageText: fmt.Sprintf("%s [%s]\n%s", cardData.Data.Name, cardSet.PrintTag, reply),
+            },
+        })
     }
 
-    return nil, nil
+    inlineQueryCacheMu.Lock()
+    inlineQueryCache[query] = inlineQueryCacheEntry{results: results, expiresAt: time.Now().Add(inlineQueryCacheTTL)}
+    inlineQueryCacheMu.Unlock()
+
+    return results
+}
+
+// waitUnlessSuperseded registers the caller as userID's latest inline query,
+// sleeps inlineQueryDebounceDelay, and reports whether a newer query for the
+// same user arrived in the meantime. Callers should skip the upstream
+// lookup and answer empty when superseded, so a burst of keystrokes only
+// ever triggers one YgoPrices lookup: the one for the last keystroke typed.
+func waitUnlessSuperseded(userID int64) (superseded bool) {
+    inlineQueryGenerationMu.Lock()
+    inlineQueryGeneration[userID]++
+    generation := inlineQueryGeneration[userID]
+    inlineQueryGenerationMu.Unlock()
+
+    time.Sleep(inlineQueryDebounceDelay)
+
+    inlineQueryGenerationMu.Lock()
+    defer inlineQueryGenerationMu.Unlock()
+
+    return inlineQueryGeneration[userID] != generation
+}
+
+// Answer an inline query with a list of results
+// https://core.telegram.org/bots/api#answerinlinequery
+func answerInlineQuery(inlineQueryID string, results []APIInlineQueryResultArticle) error {
+    reqBody := &answerInlineQueryReqBody{
+        InlineQueryID: inlineQueryID,
+        Results:       results,
+        CacheTime:     int(inlineQueryCacheTTL.Seconds()),
+    }
+    reqBytes, err := json.Marshal(reqBody)
+    if err != nil {
+        return err
+    }
+
+    var botApiUrl = fmt.Sprintf("https://api.telegram.org/bot%s/answerInlineQuery", telegramBotApiToken)
+    res, err := http.Post(botApiUrl, "application/json", bytes.NewBuffer(reqBytes))
+    if err != nil {
+        return err
+    }
+    if res.StatusCode != http.StatusOK {
+        return errors.New("unexpected status" + res.Status)
+    }
+
+    return nil
 }
 
 // Convert YgoPrices Price For Print Tag Response to Telegram Reply
-func convertYgoPricesPriceForPrintTagResponseToReply(body *ygoPricesPriceForPrintTagResponse) string {
+func convertYgoPricesPriceForPrintTagResponseToReply(body *ygoprices.PriceForPrintTagResponse) string {
     high := body.Data.PriceData.PriceData.Data.Prices.High
     average := body.Data.PriceData.PriceData.Data.Prices.Average
     low := body.Data.PriceData.PriceData.Data.Prices.Low
@@ -138,6 +247,71 @@ func convertYgoPricesPriceForPrintTagResponseToReply(body *ygoPricesPriceForPrin
     return fmt.Sprintf("Prices\nHigh :$%.2f\nAverage: $%.2f\nLow: $%.2f", high, average, low)
 }
 
+// pollWatchlist re-fetches the price of every watched print tag and sends an
+// alert when its threshold is crossed or it moves sharply since the last poll
+func pollWatchlist() {
+    all, err := watchlistStore.All()
+    if err != nil {
+        logger.Error("List all watchlist entries", "error", err)
+
+        return
+    }
+
+    for chatID, entries := range all {
+        for _, entry := range entries {
+            response, err := fetchCardPriceByPrintTagMetered(entry.PrintTag)
+            if err != nil || response == nil {
+                continue
+            }
+
+            prices := response.Data.PriceData.PriceData.Data.Prices
+            recordWatchlistPriceSnapshot(entry.PrintTag, prices.High, prices.Average, prices.Low)
+
+            if watchlistShouldAlert(entry.Threshold, entry.LastAverage, prices.Average) {
+                sendReply(chatID, fmt.Sprintf(
+                    "%s [%s] price alert!\nAverage: $%.2f (7d shift %.1f%%, 30d shift %.1f%%)",
+                    response.Data.Name, entry.PrintTag, prices.Average, prices.Shift7, prices.Shift30,
+                ))
+            }
+
+            if err := watchlistStore.UpdateLastAverage(chatID, entry.PrintTag, prices.Average); err != nil {
+                logger.Error("Update watchlist entry", "error", err)
+            }
+        }
+    }
+}
+
+// watchlistShouldAlert reports whether a watched entry's new average price
+// warrants an alert: either it just crossed the entry's threshold (as
+// opposed to having already been at or above it last poll, which would
+// otherwise re-fire every poll), or it moved by at least
+// watchlistShiftAlertPct since the last poll
+func watchlistShouldAlert(threshold, lastAverage, average float64) bool {
+    crossedThreshold := threshold > 0 && lastAverage > 0 && lastAverage < threshold && average >= threshold
+    movedSharply := lastAverage > 0 && math.Abs(average-lastAverage)/lastAverage >= watchlistShiftAlertPct
+
+    return crossedThreshold || movedSharply
+}
+
+// startWatchlistScheduler polls the watchlist on the given interval until
+// stop is closed. Each poll is tracked in watchlistWG so shutdown can wait
+// for an in-flight poll to finish.
+func startWatchlistScheduler(interval time.Duration, stop <-chan struct{}) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ticker.C:
+            watchlistWG.Add(1)
+            pollWatchlist()
+            watchlistWG.Done()
+        case <-stop:
+            return
+        }
+    }
+}
+
 // This handler is called everytime telegram sends us a webhook event
 func webhookHandler(res http.ResponseWriter, req *http.Request) {
     // First, decode the JSON response body
@@ -147,30 +321,86 @@ func webhookHandler(res http.ResponseWriter, req *http.Request) {
         return
     }
 
-    var text = strings.ToLower(body.Message.Text)
-    if strings.Contains(text, "/priceprinttag") {
-        parts := strings.Split(body.Message.Text, " ")
-        if len(parts) < 2 {
-            sendReply(body.Message.Chat.ID, "Error fetching card price!")
-        } else {
-            printTag := parts[1]
-            response, err := fetchCardPriceByPrintTag(printTag)
-            if err != nil || response == nil {
-                sendReply(body.Message.Chat.ID, "Error fetching card price!")
-            } else {
-                reply := convertYgoPricesPriceForPrintTagResponseToReply(response)
-                sendReply(body.Message.Chat.ID, reply)
-            }
+    webhookUpdatesTotal.Inc()
+
+    if body.InlineQuery.ID != "" {
+        results := buildInlineQueryResults(body.InlineQuery.From.ID, body.InlineQuery.Query)
+        if err := answerInlineQuery(body.InlineQuery.ID, results); err != nil {
+            logger.Error("answer inline query", "error", err)
         }
-    } else {
-        sendReply(body.Message.Chat.ID, "Invalid command!")
+
+        return
+    }
+
+    reply, err := dispatcher.Dispatch(req.Context(), body.Message.Chat.ID, body.Message.Text)
+    if err != nil {
+        reply = "Error processing command!"
     }
+    sendReply(body.Message.Chat.ID, reply)
 
     // log a confirmation message if the message is sent successfully
     logger.Info("reply sent", body.Message.Chat.ID)
 }
 
 func main() {
+    // seed the card database from disk so fuzzy lookups work immediately,
+    // then keep it fresh with a background refresh against YGOPRODeck
+    if cardDBCachePath == "" {
+        cardDBCachePath = "carddb_cache.json"
+    }
+    if err := cardDB.LoadFromDisk(cardDBCachePath); err != nil {
+        logger.Warn("Could not load card database cache from disk", "error", err)
+    }
+
+    cardDBRefreshInterval := 24 * time.Hour
+    if v := os.Getenv("CARD_DB_REFRESH_INTERVAL"); v != "" {
+        if d, err := time.ParseDuration(v); err == nil {
+            cardDBRefreshInterval = d
+        }
+    }
+
+    stopCardDBRefresh := make(chan struct{})
+    go cardDB.StartAutoRefresh(cardDBRefreshInterval, cardDBCachePath, stopCardDBRefresh)
+    defer close(stopCardDBRefresh)
+
+    // open the watchlist store and start the background price-alert scheduler
+    if watchlistDBPath == "" {
+        watchlistDBPath = "watchlist.db"
+    }
+
+    var err error
+    watchlistStore, err = watchlist.Open(watchlistDBPath)
+    if err != nil {
+        logger.Error("Error opening watchlist store", "error", err)
+        os.Exit(1)
+    }
+    defer watchlistStore.Close()
+
+    watchlistPollInterval := 15 * time.Minute
+    if v := os.Getenv("WATCHLIST_POLL_INTERVAL"); v != "" {
+        if d, err := time.ParseDuration(v); err == nil {
+            watchlistPollInterval = d
+        }
+    }
+
+    stopWatchlistScheduler := make(chan struct{})
+    go startWatchlistScheduler(watchlistPollInterval, stopWatchlistScheduler)
+
+    // wire up the command dispatcher: logging/metrics/rate-limiting/panic
+    // recovery run around every command, so individual commands stay focused
+    // on their own behavior
+    dispatcher = bot.NewDispatcher(logger,
+        bot.RecoverMiddleware(logger),
+        bot.LoggingMiddleware(logger),
+        bot.MetricsMiddleware(func(commandName string) { commandsProcessedTotal.WithLabelValues(commandName).Inc() }),
+        bot.RateLimitMiddleware(1, 5),
+    )
+    dispatcher.Register(&bot.PricePrintTagCommand{PriceForPrintTag: fetchCardPriceByPrintTagMetered})
+    dispatcher.Register(&bot.PriceCommand{CardDB: cardDB, PriceForPrintTag: fetchCardPriceByPrintTagMetered})
+    dispatcher.Register(&bot.WatchCommand{Store: watchlistStore})
+    dispatcher.Register(&bot.UnwatchCommand{Store: watchlistStore})
+    dispatcher.Register(&bot.WatchlistCommand{Store: watchlistStore})
+
     // create a new serve mux and register the handlers
     sm := mux.NewRouter()
 
@@ -178,6 +408,11 @@ func main() {
     getR := sm.Methods(http.MethodPost).Subrouter()
     getR.HandleFunc("/", webhookHandler)
 
+    // operational endpoints
+    adminR := sm.Methods(http.MethodGet).Subrouter()
+    adminR.Handle("/metrics", promhttp.Handler())
+    adminR.HandleFunc("/healthz", healthzHandler)
+
     // CORS
     ch := gohandlers.CORS(gohandlers.AllowedOrigins([]string{"*"}))
 
@@ -211,7 +446,13 @@ func main() {
     sig := <-c
     logger.Info("Got signal:", sig)
 
+    // stop scheduling new watchlist polls and let any in-flight poll finish
+    // before tearing down the server
+    close(stopWatchlistScheduler)
+    watchlistWG.Wait()
+
     // gracefully shutdown the server, waiting max 30 seconds for current operations to complete
-    ctx, _ := context.WithTimeout(context.Background(), 30*time.Second)
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
     s.Shutdown(ctx)
 }
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestWatchlistShouldAlert(t *testing.T) {
+    tests := []struct {
+        name        string
+        threshold   float64
+        lastAverage float64
+        average     float64
+        want        bool
+    }{
+        {"no threshold no history", 0, 0, 10, false},
+        {"below threshold no history", 20, 0, 10, false},
+        {"no history yet, still above threshold", 20, 0, 25, false},
+        {"crosses threshold", 20, 19.5, 20.2, true},
+        {"equals threshold", 20, 19.9, 20, true},
+        {"already at or above threshold, no re-alert", 20, 20, 20.5, false},
+        {"stable average below threshold", 20, 10, 10.5, false},
+        {"sharp upward shift", 0, 10, 11.5, true},
+        {"sharp downward shift", 0, 10, 8.5, true},
+        {"small shift below alert pct", 0, 10, 10.5, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := watchlistShouldAlert(tt.threshold, tt.lastAverage, tt.average); got != tt.want {
+                t.Errorf("watchlistShouldAlert(%v, %v, %v) = %v, want %v", tt.threshold, tt.lastAverage, tt.average, got, tt.want)
+            }
+        })
+    }
+}
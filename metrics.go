@@ -0,0 +1,175 @@
+package main
+
+import (
+    "net/http"
+    "sync"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+
+    "github.com/Pr3d4dor/telegram-ygoprices-bot/pkg/ygoprices"
+)
+
+var (
+    webhookUpdatesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+        Name: "telegram_webhook_updates_total",
+        Help: "Total number of Telegram webhook updates received",
+    })
+
+    commandsProcessedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Name: "telegram_commands_processed_total",
+        Help: "Total number of bot commands processed, labeled by command",
+    }, []string{"command"})
+
+    replySendFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+        Name: "telegram_reply_send_failures_total",
+        Help: "Total number of failed attempts to send a Telegram reply",
+    })
+
+    ygoPricesRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Name: "ygoprices_api_requests_total",
+        Help: "Total number of requests made to the YgoPrices API, labeled by status",
+    }, []string{"status"})
+
+    ygoPricesRequestDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+        Name:    "ygoprices_api_request_duration_seconds",
+        Help:    "Latency of YgoPrices API requests",
+        Buckets: prometheus.DefBuckets,
+    })
+)
+
+func init() {
+    prometheus.MustRegister(
+        webhookUpdatesTotal,
+        commandsProcessedTotal,
+        replySendFailuresTotal,
+        ygoPricesRequestsTotal,
+        ygoPricesRequestDuration,
+    )
+    prometheus.MustRegister(newWatchlistPriceCollector())
+}
+
+// fetchCardPriceByPrintTagMetered wraps ygoPricesClient.PriceForPrintTag with
+// request latency and status metrics
+func fetchCardPriceByPrintTagMetered(printTag string) (*ygoprices.PriceForPrintTagResponse, error) {
+    start := time.Now()
+    response, err := ygoPricesClient.PriceForPrintTag(printTag)
+    ygoPricesRequestDuration.Observe(time.Since(start).Seconds())
+    ygoPricesRequestsTotal.WithLabelValues(ygoPricesRequestStatus(response != nil, err)).Inc()
+
+    return response, err
+}
+
+// fetchCardDataMetered wraps ygoPricesClient.CardData with request latency
+// and status metrics
+func fetchCardDataMetered(name string) (*ygoprices.CardDataResponse, error) {
+    start := time.Now()
+    response, err := ygoPricesClient.CardData(name)
+    ygoPricesRequestDuration.Observe(time.Since(start).Seconds())
+    ygoPricesRequestsTotal.WithLabelValues(ygoPricesRequestStatus(response != nil, err)).Inc()
+
+    return response, err
+}
+
+func ygoPricesRequestStatus(found bool, err error) string {
+    if err != nil {
+        return "error"
+    }
+    if !found {
+        return "not_found"
+    }
+
+    return "success"
+}
+
+// watchlistPriceSnapshot is the last-seen price for a watched print tag, as
+// observed by the watchlist poller
+type watchlistPriceSnapshot struct {
+    High    float64
+    Average float64
+    Low     float64
+}
+
+var (
+    watchlistPriceCacheMu sync.RWMutex
+    watchlistPriceCache   = map[string]watchlistPriceSnapshot{}
+)
+
+// recordWatchlistPriceSnapshot stores the latest polled price for a print
+// tag, for watchlistPriceCollector to expose without touching the network
+func recordWatchlistPriceSnapshot(printTag string, high, average, low float64) {
+    watchlistPriceCacheMu.Lock()
+    watchlistPriceCache[printTag] = watchlistPriceSnapshot{High: high, Average: average, Low: low}
+    watchlistPriceCacheMu.Unlock()
+}
+
+func getWatchlistPriceSnapshot(printTag string) (watchlistPriceSnapshot, bool) {
+    watchlistPriceCacheMu.RLock()
+    snapshot, ok := watchlistPriceCache[printTag]
+    watchlistPriceCacheMu.RUnlock()
+
+    return snapshot, ok
+}
+
+// watchlistPriceCollector exposes the current high/average/low price of
+// every watched card as gauges, read from the cache the watchlist poller
+// fills in on its own schedule rather than fetched live on every scrape
+type watchlistPriceCollector struct {
+    highDesc    *prometheus.Desc
+    averageDesc *prometheus.Desc
+    lowDesc     *prometheus.Desc
+}
+
+func newWatchlistPriceCollector() *watchlistPriceCollector {
+    labels := []string{"print_tag"}
+
+    return &watchlistPriceCollector{
+        highDesc:    prometheus.NewDesc("ygo_card_price_high", "Highest known price for a watched card print", labels, nil),
+        averageDesc: prometheus.NewDesc("ygo_card_price_average", "Average known price for a watched card print", labels, nil),
+        lowDesc:     prometheus.NewDesc("ygo_card_price_low", "Lowest known price for a watched card print", labels, nil),
+    }
+}
+
+func (c *watchlistPriceCollector) Describe(ch chan<- *prometheus.Desc) {
+    ch <- c.highDesc
+    ch <- c.averageDesc
+    ch <- c.lowDesc
+}
+
+func (c *watchlistPriceCollector) Collect(ch chan<- prometheus.Metric) {
+    if watchlistStore == nil {
+        return
+    }
+
+    all, err := watchlistStore.All()
+    if err != nil {
+        logger.Error("Collect watchlist prices", "error", err)
+
+        return
+    }
+
+    seen := map[string]bool{}
+    for _, entries := range all {
+        for _, entry := range entries {
+            if seen[entry.PrintTag] {
+                continue
+            }
+            seen[entry.PrintTag] = true
+
+            snapshot, ok := getWatchlistPriceSnapshot(entry.PrintTag)
+            if !ok {
+                continue
+            }
+
+            ch <- prometheus.MustNewConstMetric(c.highDesc, prometheus.GaugeValue, snapshot.High, entry.PrintTag)
+            ch <- prometheus.MustNewConstMetric(c.averageDesc, prometheus.GaugeValue, snapshot.Average, entry.PrintTag)
+            ch <- prometheus.MustNewConstMetric(c.lowDesc, prometheus.GaugeValue, snapshot.Low, entry.PrintTag)
+        }
+    }
+}
+
+// healthzHandler is a liveness endpoint for the bot process
+func healthzHandler(res http.ResponseWriter, req *http.Request) {
+    res.WriteHeader(http.StatusOK)
+    res.Write([]byte("ok"))
+}
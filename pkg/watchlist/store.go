@@ -0,0 +1,163 @@
+// Package watchlist persists per-chat card watchlists in an embedded BoltDB
+// file so subscriptions survive bot restarts.
+package watchlist
+
+import (
+    "encoding/json"
+    "strconv"
+    "time"
+
+    "go.etcd.io/bbolt"
+)
+
+// Entry is a single watched card within a chat's watchlist
+type Entry struct {
+    PrintTag    string  `json:"print_tag"`
+    Threshold   float64 `json:"threshold"`
+    LastAverage float64 `json:"last_average"`
+}
+
+// Store is a BoltDB-backed watchlist, with one bucket per chat ID
+type Store struct {
+    db *bbolt.DB
+}
+
+// Open opens (creating if needed) the watchlist database at path
+func Open(path string) (*Store, error) {
+    db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+    if err != nil {
+        return nil, err
+    }
+
+    return &Store{db: db}, nil
+}
+
+// Close closes the underlying database
+func (s *Store) Close() error {
+    return s.db.Close()
+}
+
+func chatBucketName(chatID int64) []byte {
+    return []byte(strconv.FormatInt(chatID, 10))
+}
+
+// Add registers (or updates) a watched print tag for a chat
+func (s *Store) Add(chatID int64, entry Entry) error {
+    return s.db.Update(func(tx *bbolt.Tx) error {
+        bucket, err := tx.CreateBucketIfNotExists(chatBucketName(chatID))
+        if err != nil {
+            return err
+        }
+
+        value, err := json.Marshal(entry)
+        if err != nil {
+            return err
+        }
+
+        return bucket.Put([]byte(entry.PrintTag), value)
+    })
+}
+
+// Remove unregisters a print tag from a chat's watchlist
+func (s *Store) Remove(chatID int64, printTag string) error {
+    return s.db.Update(func(tx *bbolt.Tx) error {
+        bucket := tx.Bucket(chatBucketName(chatID))
+        if bucket == nil {
+            return nil
+        }
+
+        return bucket.Delete([]byte(printTag))
+    })
+}
+
+// List returns every entry watched by a chat
+func (s *Store) List(chatID int64) ([]Entry, error) {
+    var entries []Entry
+
+    err := s.db.View(func(tx *bbolt.Tx) error {
+        bucket := tx.Bucket(chatBucketName(chatID))
+        if bucket == nil {
+            return nil
+        }
+
+        return bucket.ForEach(func(_, value []byte) error {
+            var entry Entry
+            if err := json.Unmarshal(value, &entry); err != nil {
+                return err
+            }
+
+            entries = append(entries, entry)
+
+            return nil
+        })
+    })
+
+    return entries, err
+}
+
+// All returns every watched entry for every chat, keyed by chat ID
+func (s *Store) All() (map[int64][]Entry, error) {
+    all := map[int64][]Entry{}
+
+    err := s.db.View(func(tx *bbolt.Tx) error {
+        return tx.ForEach(func(name []byte, bucket *bbolt.Bucket) error {
+            chatID, err := strconv.ParseInt(string(name), 10, 64)
+            if err != nil {
+                return nil
+            }
+
+            var entries []Entry
+            err = bucket.ForEach(func(_, value []byte) error {
+                var entry Entry
+                if err := json.Unmarshal(value, &entry); err != nil {
+                    return err
+                }
+
+                entries = append(entries, entry)
+
+                return nil
+            })
+            if err != nil {
+                return err
+            }
+
+            if len(entries) > 0 {
+                all[chatID] = entries
+            }
+
+            return nil
+        })
+    })
+
+    return all, err
+}
+
+// UpdateLastAverage records the most recently observed average price for a
+// watched print tag, used to detect sharp moves on the next poll
+func (s *Store) UpdateLastAverage(chatID int64, printTag string, average float64) error {
+    return s.db.Update(func(tx *bbolt.Tx) error {
+        bucket, err := tx.CreateBucketIfNotExists(chatBucketName(chatID))
+        if err != nil {
+            return err
+        }
+
+        existing := bucket.Get([]byte(printTag))
+        if existing == nil {
+            return nil
+        }
+
+        var entry Entry
+        if err := json.Unmarshal(existing, &entry); err != nil {
+            return err
+        }
+
+        entry.LastAverage = average
+
+        value, err := json.Marshal(entry)
+        if err != nil {
+            return err
+        }
+
+        return bucket.Put([]byte(printTag), value)
+    })
+}
@@ -0,0 +1,227 @@
+package ygoprices
+
+import (
+    "encoding/json"
+    "net/http"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+// cardInfoURL is the full YGOPRODeck card database dump, used to resolve a
+// human-typed card name to the print tags needed to query YgoPrices
+const cardInfoURL = "https://db.ygoprodeck.com/api/v7/cardinfo.php"
+
+// CardMatch is a single card resolved from the database, along with the set
+// codes (print tags) it has been printed under
+type CardMatch struct {
+    ID        int      `json:"id"`
+    Name      string   `json:"name"`
+    PrintTags []string `json:"print_tags"`
+}
+
+// cardInfoResponse mimics the cardinfo.php response body
+type cardInfoResponse struct {
+    Data []struct {
+        ID       int    `json:"id"`
+        Name     string `json:"name"`
+        CardSets []struct {
+            SetCode string `json:"set_code"`
+        } `json:"card_sets"`
+    } `json:"data"`
+}
+
+// CardDatabase is an in-memory, periodically refreshed index of every card
+// known to YGOPRODeck, backed by a flat JSON file on disk so the bot has a
+// usable index immediately on startup
+type CardDatabase struct {
+    logger hclog.Logger
+
+    mu    sync.RWMutex
+    cards []CardMatch
+}
+
+// NewCardDatabase creates an empty card database. Call LoadFromDisk to seed
+// it from a previous cache and Refresh (or StartAutoRefresh) to populate or
+// update it from YGOPRODeck.
+func NewCardDatabase(logger hclog.Logger) *CardDatabase {
+    return &CardDatabase{logger: logger}
+}
+
+// LoadFromDisk seeds the database from a previously cached JSON dump
+func (d *CardDatabase) LoadFromDisk(path string) error {
+    file, err := os.Open(path)
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+
+    var cards []CardMatch
+    if err := json.NewDecoder(file).Decode(&cards); err != nil {
+        return err
+    }
+
+    d.mu.Lock()
+    d.cards = cards
+    d.mu.Unlock()
+
+    return nil
+}
+
+// saveToDisk persists the current database as a flat JSON file
+func (d *CardDatabase) saveToDisk(path string) error {
+    d.mu.RLock()
+    cards := d.cards
+    d.mu.RUnlock()
+
+    file, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+
+    return json.NewEncoder(file).Encode(cards)
+}
+
+// Refresh re-downloads the full card dump from YGOPRODeck and persists it to
+// cachePath
+func (d *CardDatabase) Refresh(cachePath string) error {
+    resp, err := http.Get(cardInfoURL)
+    if err != nil {
+        d.logger.Error("Ygoprodeck cardinfo request error", "error", err)
+
+        return err
+    }
+    defer resp.Body.Close()
+
+    cardInfo := &cardInfoResponse{}
+    if err := json.NewDecoder(resp.Body).Decode(cardInfo); err != nil {
+        d.logger.Error("Parse ygoprodeck cardinfo response", "error", err)
+
+        return err
+    }
+
+    cards := make([]CardMatch, 0, len(cardInfo.Data))
+    for _, c := range cardInfo.Data {
+        printTags := make([]string, 0, len(c.CardSets))
+        for _, set := range c.CardSets {
+            if set.SetCode != "" {
+                printTags = append(printTags, set.SetCode)
+            }
+        }
+
+        cards = append(cards, CardMatch{ID: c.ID, Name: c.Name, PrintTags: printTags})
+    }
+
+    d.mu.Lock()
+    d.cards = cards
+    d.mu.Unlock()
+
+    if err := d.saveToDisk(cachePath); err != nil {
+        d.logger.Warn("Could not persist card database cache", "error", err)
+    }
+
+    return nil
+}
+
+// StartAutoRefresh calls Refresh immediately, then again on the given
+// interval until stop is closed, so the database is usable as soon as
+// possible on a fresh deployment instead of waiting for the first tick.
+// Meant to be run as a goroutine started from main.
+func (d *CardDatabase) StartAutoRefresh(interval time.Duration, cachePath string, stop <-chan struct{}) {
+    if err := d.Refresh(cachePath); err != nil {
+        d.logger.Error("Card database refresh failed", "error", err)
+    }
+
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ticker.C:
+            if err := d.Refresh(cachePath); err != nil {
+                d.logger.Error("Card database refresh failed", "error", err)
+            }
+        case <-stop:
+            return
+        }
+    }
+}
+
+// ScoredCardMatch is a CardMatch together with the Levenshtein distance
+// between its name and the query that produced it, so callers can judge how
+// confident a match is
+type ScoredCardMatch struct {
+    CardMatch
+    Distance int
+}
+
+// Search resolves a human-typed, possibly misspelled card name to the
+// closest matches in the database, ordered by similarity, capped at limit
+func (d *CardDatabase) Search(query string, limit int) []ScoredCardMatch {
+    query = strings.ToLower(strings.TrimSpace(query))
+    if query == "" {
+        return nil
+    }
+
+    d.mu.RLock()
+    cards := d.cards
+    d.mu.RUnlock()
+
+    scored := make([]ScoredCardMatch, 0, len(cards))
+    for _, card := range cards {
+        scored = append(scored, ScoredCardMatch{CardMatch: card, Distance: levenshtein(query, strings.ToLower(card.Name))})
+    }
+
+    sort.Slice(scored, func(i, j int) bool {
+        return scored[i].Distance < scored[j].Distance
+    })
+
+    if limit > len(scored) {
+        limit = len(scored)
+    }
+
+    return scored[:limit]
+}
+
+// levenshtein computes the edit distance between two strings
+func levenshtein(a, b string) int {
+    ra, rb := []rune(a), []rune(b)
+
+    prev := make([]int, len(rb)+1)
+    curr := make([]int, len(rb)+1)
+    for j := range prev {
+        prev[j] = j
+    }
+
+    for i := 1; i <= len(ra); i++ {
+        curr[0] = i
+        for j := 1; j <= len(rb); j++ {
+            cost := 1
+            if ra[i-1] == rb[j-1] {
+                cost = 0
+            }
+
+            curr[j] = min3(curr[j-1]+1, prev[j]+1, prev[j-1]+cost)
+        }
+        prev, curr = curr, prev
+    }
+
+    return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+    m := a
+    if b < m {
+        m = b
+    }
+    if c < m {
+        m = c
+    }
+
+    return m
+}
@@ -0,0 +1,72 @@
+package ygoprices
+
+import (
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+func TestLevenshtein(t *testing.T) {
+    tests := []struct {
+        a, b string
+        want int
+    }{
+        {"", "", 0},
+        {"dark magician", "dark magician", 0},
+        {"dark magican", "dark magician", 1},
+        {"drak magician", "dark magician", 2},
+        {"kitten", "sitting", 3},
+        {"", "abc", 3},
+    }
+
+    for _, tt := range tests {
+        if got := levenshtein(tt.a, tt.b); got != tt.want {
+            t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+        }
+    }
+}
+
+func TestCardDatabaseSearch(t *testing.T) {
+    db := NewCardDatabase(hclog.NewNullLogger())
+    db.cards = []CardMatch{
+        {ID: 1, Name: "Dark Magician", PrintTags: []string{"LOB-005"}},
+        {ID: 2, Name: "Dark Magician Girl", PrintTags: []string{"MFC-000"}},
+        {ID: 3, Name: "Blue-Eyes White Dragon", PrintTags: []string{"LOB-001"}},
+    }
+
+    matches := db.Search("dark magican", 3)
+    if len(matches) != 3 {
+        t.Fatalf("len(matches) = %d, want 3", len(matches))
+    }
+    if matches[0].Name != "Dark Magician" {
+        t.Errorf("matches[0].Name = %q, want %q", matches[0].Name, "Dark Magician")
+    }
+    if matches[0].Distance != 1 {
+        t.Errorf("matches[0].Distance = %d, want 1", matches[0].Distance)
+    }
+    if matches[0].Distance > matches[1].Distance || matches[1].Distance > matches[2].Distance {
+        t.Errorf("matches not sorted by distance: %+v", matches)
+    }
+}
+
+func TestCardDatabaseSearchEmptyQuery(t *testing.T) {
+    db := NewCardDatabase(hclog.NewNullLogger())
+    db.cards = []CardMatch{{ID: 1, Name: "Dark Magician"}}
+
+    if matches := db.Search("   ", 3); matches != nil {
+        t.Errorf("Search(\"   \") = %v, want nil", matches)
+    }
+}
+
+func TestCardDatabaseSearchLimitsResults(t *testing.T) {
+    db := NewCardDatabase(hclog.NewNullLogger())
+    db.cards = []CardMatch{
+        {ID: 1, Name: "Dark Magician"},
+        {ID: 2, Name: "Dark Magician Girl"},
+        {ID: 3, Name: "Dark Magician of Chaos"},
+    }
+
+    if matches := db.Search("dark magician", 1); len(matches) != 1 {
+        t.Errorf("len(matches) = %d, want 1", len(matches))
+    }
+}
@@ -0,0 +1,124 @@
+// Package ygoprices is a thin client for the YgoPrices API
+// (https://yugiohprices.docs.apiary.io), used to look up card prices by
+// print tag.
+package ygoprices
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/url"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+const baseURL = "https://yugiohprices.com/api"
+
+// Client talks to the YgoPrices API
+type Client struct {
+    logger hclog.Logger
+}
+
+// NewClient creates a YgoPrices API client
+func NewClient(logger hclog.Logger) *Client {
+    return &Client{logger: logger}
+}
+
+// PriceForPrintTagResponse mimics the price_for_print_tag response body
+// https://yugiohprices.docs.apiary.io/#reference/checking-card-prices/check-price-for-cards-print-tag/check-price-for-card's-print-tag
+type PriceForPrintTagResponse struct {
+    Status string `json:"status"`
+    Data   struct {
+        Name      string      `json:"name"`
+        CardType  string      `json:"card_type"`
+        Property  interface{} `json:"property"`
+        Family    string      `json:"family"`
+        Type      string      `json:"type"`
+        PriceData struct {
+            Name      string `json:"name"`
+            PrintTag  string `json:"print_tag"`
+            Rarity    string `json:"rarity"`
+            PriceData struct {
+                Status string `json:"status"`
+                Data   struct {
+                    Listings []interface{} `json:"listings"`
+                    Prices   struct {
+                        High      float64 `json:"high"`
+                        Low       float64 `json:"low"`
+                        Average   float64 `json:"average"`
+                        Shift     float64 `json:"shift"`
+                        Shift3    float64 `json:"shift_3"`
+                        Shift7    float64 `json:"shift_7"`
+                        Shift21   float64 `json:"shift_21"`
+                        Shift30   float64 `json:"shift_30"`
+                        Shift90   float64 `json:"shift_90"`
+                        Shift180  float64 `json:"shift_180"`
+                        Shift365  float64 `json:"shift_365"`
+                        UpdatedAt string  `json:"updated_at"`
+                    } `json:"prices"`
+                } `json:"data"`
+            } `json:"price_data"`
+        } `json:"price_data"`
+    } `json:"data"`
+}
+
+// PriceForPrintTag fetches card prices for a single print tag
+func (c *Client) PriceForPrintTag(printTag string) (*PriceForPrintTagResponse, error) {
+    resp, err := http.Get(baseURL + "/price_for_print_tag/" + printTag)
+    if err != nil {
+        c.logger.Error("Ygoprices price for print tag request error", "error", err)
+
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    priceResponse := &PriceForPrintTagResponse{}
+    if err := json.NewDecoder(resp.Body).Decode(priceResponse); err != nil {
+        c.logger.Error("Parse ygoprices price for print tag response", "error", err)
+
+        return nil, err
+    }
+
+    if priceResponse.Status == "success" {
+        return priceResponse, nil
+    }
+
+    return nil, nil
+}
+
+// CardDataResponse mimics the card_data response body
+// https://yugiohprices.docs.apiary.io/#reference/card-data/get-card-data/get-card-data
+type CardDataResponse struct {
+    Status string `json:"status"`
+    Data   struct {
+        Name     string `json:"name"`
+        Image    string `json:"image"`
+        CardSets []struct {
+            PrintTag string `json:"print_tag"`
+            Rarity   string `json:"rarity"`
+        } `json:"card_sets"`
+    } `json:"data"`
+}
+
+// CardData fetches the known print tags for a card by name
+func (c *Client) CardData(name string) (*CardDataResponse, error) {
+    resp, err := http.Get(baseURL + "/card_data/" + url.QueryEscape(name))
+    if err != nil {
+        c.logger.Error("Ygoprices card data request error", "error", err)
+
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    cardDataResponse := &CardDataResponse{}
+    if err := json.NewDecoder(resp.Body).Decode(cardDataResponse); err != nil {
+        c.logger.Error("Parse ygoprices card data response", "error", err)
+
+        return nil, err
+    }
+
+    if cardDataResponse.Status == "success" {
+        return cardDataResponse, nil
+    }
+
+    return nil, nil
+}